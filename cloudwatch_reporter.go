@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+// CloudWatchReporter publishes per-bucket size/object counts as custom
+// CloudWatch metrics under the configured namespace, so they can be
+// graphed and alarmed on alongside AWS's own S3 storage metrics.
+type CloudWatchReporter struct {
+	Namespace string
+}
+
+func NewCloudWatchReporter() *CloudWatchReporter {
+	namespace := os.Getenv("CLOUDWATCH_NAMESPACE")
+	if namespace == "" {
+		namespace = "S3MetricsExporter"
+	}
+	return &CloudWatchReporter{Namespace: namespace}
+}
+
+func (r *CloudWatchReporter) Report(ctx context.Context, results map[string]*BucketInfo) error {
+	sdkConfig, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("failed loading AWS config: %w", err)
+	}
+	client := cloudwatch.NewFromConfig(sdkConfig)
+
+	var data []types.MetricDatum
+	for _, bucketInfo := range results {
+		// CloudWatch rejects dimension values shorter than 1 character, and
+		// Profile is "" in the common single-account case (no S3_PROFILES).
+		profile := bucketInfo.Profile
+		if profile == "" {
+			profile = "default"
+		}
+		dims := []types.Dimension{
+			{Name: aws.String("BucketName"), Value: aws.String(bucketInfo.Name)},
+			{Name: aws.String("Profile"), Value: aws.String(profile)},
+		}
+		data = append(data,
+			types.MetricDatum{
+				MetricName: aws.String("BucketSizeBytes"),
+				Dimensions: dims,
+				Unit:       types.StandardUnitBytes,
+				Value:      aws.Float64(float64(bucketInfo.Size)),
+			},
+			types.MetricDatum{
+				MetricName: aws.String("NumberOfObjects"),
+				Dimensions: dims,
+				Unit:       types.StandardUnitCount,
+				Value:      aws.Float64(float64(bucketInfo.Objects)),
+			},
+		)
+	}
+
+	// PutMetricData caps a single call at 1000 data points.
+	for start := 0; start < len(data); start += 1000 {
+		end := start + 1000
+		if end > len(data) {
+			end = len(data)
+		}
+		_, err := client.PutMetricData(ctx, &cloudwatch.PutMetricDataInput{
+			Namespace:  aws.String(r.Namespace),
+			MetricData: data[start:end],
+		})
+		if err != nil {
+			return fmt.Errorf("failed publishing metrics: %w", err)
+		}
+	}
+	return nil
+}
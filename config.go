@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// S3Config holds the connection settings needed to talk to either real
+// AWS or an S3-compatible service such as MinIO, Ceph, or Wasabi.
+type S3Config struct {
+	EndpointURL     string
+	Region          string
+	ForcePathStyle  bool
+	AccessKeyID     string
+	SecretAccessKey string
+	CABundlePath    string
+
+	// RoleARN, if set, is assumed via STS on top of whichever credentials
+	// resolve first (static keys, or the default chain including EC2/ECS
+	// IMDS). RoleSessionName defaults to the SDK's own default when empty.
+	RoleARN         string
+	RoleSessionName string
+
+	// Profiles lists the named AWS profiles to scan. An empty slice means
+	// "use the default credentials chain" (static keys, env vars, shared
+	// config, or EC2/ECS IMDS, in that order), with RoleARN, if set,
+	// assumed on top of whichever of those resolves.
+	Profiles []string
+}
+
+// LoadS3Config reads S3 connection settings from the environment.
+func LoadS3Config() S3Config {
+	cfg := S3Config{
+		EndpointURL:     os.Getenv("S3_ENDPOINT_URL"),
+		Region:          os.Getenv("S3_REGION"),
+		AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		CABundlePath:    os.Getenv("S3_CA_BUNDLE"),
+		RoleARN:         os.Getenv("S3_ROLE_ARN"),
+		RoleSessionName: os.Getenv("S3_ROLE_SESSION_NAME"),
+	}
+
+	if forcePathStyle, err := strconv.ParseBool(os.Getenv("S3_FORCE_PATH_STYLE")); err == nil {
+		cfg.ForcePathStyle = forcePathStyle
+	}
+
+	if raw := os.Getenv("S3_PROFILES"); raw != "" {
+		for _, profile := range strings.Split(raw, ",") {
+			if profile = strings.TrimSpace(profile); profile != "" {
+				cfg.Profiles = append(cfg.Profiles, profile)
+			}
+		}
+	}
+
+	return cfg
+}
+
+// defaultAWSConfig loads the default AWS credentials chain with no
+// endpoint/region overrides, for AWS-only clients (e.g. CloudWatch) that
+// don't need the S3-compatible-endpoint plumbing below.
+func defaultAWSConfig(ctx context.Context) (aws.Config, error) {
+	return config.LoadDefaultConfig(ctx)
+}
+
+// NewS3Client builds an S3 client for the given profile ("" uses the
+// default credentials chain). It wires up a custom endpoint, region,
+// path-style addressing, static/assume-role credentials, and CA bundle
+// when configured, so the tool can talk to MinIO/Ceph/Wasabi as easily
+// as AWS.
+func NewS3Client(ctx context.Context, cfg S3Config, profile string) (*s3.Client, error) {
+	var opts []func(*config.LoadOptions) error
+
+	if cfg.Region != "" {
+		opts = append(opts, config.WithRegion(cfg.Region))
+	}
+	if profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(profile))
+	}
+	if cfg.AccessKeyID != "" && cfg.SecretAccessKey != "" {
+		opts = append(opts, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
+		))
+	}
+	if cfg.EndpointURL != "" {
+		resolver := aws.EndpointResolverWithOptionsFunc(
+			func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+				return aws.Endpoint{URL: cfg.EndpointURL, SigningRegion: cfg.Region}, nil
+			},
+		)
+		opts = append(opts, config.WithEndpointResolverWithOptions(resolver))
+	}
+	if cfg.CABundlePath != "" {
+		httpClient, err := httpClientWithCABundle(cfg.CABundlePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed loading CA bundle: %w", err)
+		}
+		opts = append(opts, config.WithHTTPClient(httpClient))
+	}
+
+	// The standard retryer already backs off with jitter on throttling
+	// (SlowDown, RequestLimitExceeded) and 5xx responses; we just widen
+	// the attempt budget for buckets under heavy load.
+	opts = append(opts, config.WithRetryer(func() aws.Retryer {
+		return retry.NewStandard(func(o *retry.StandardOptions) {
+			o.MaxAttempts = 8
+		})
+	}))
+
+	sdkConfig, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed loading AWS config: %w", err)
+	}
+
+	if cfg.RoleARN != "" {
+		stsClient := sts.NewFromConfig(sdkConfig)
+		provider := stscreds.NewAssumeRoleProvider(stsClient, cfg.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+			if cfg.RoleSessionName != "" {
+				o.RoleSessionName = cfg.RoleSessionName
+			}
+		})
+		sdkConfig.Credentials = aws.NewCredentialsCache(provider)
+	}
+
+	return s3.NewFromConfig(sdkConfig, func(o *s3.Options) {
+		o.UsePathStyle = cfg.ForcePathStyle
+	}), nil
+}
+
+func httpClientWithCABundle(path string) (*http.Client, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		},
+	}, nil
+}
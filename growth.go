@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// SlackBlock is a single Block Kit block. Only the block types and
+// fields this tool emits (section, divider) are modeled.
+type SlackBlock struct {
+	Type   string       `json:"type"`
+	Text   *SlackText   `json:"text,omitempty"`
+	Fields []*SlackText `json:"fields,omitempty"`
+}
+
+// SlackText is a Block Kit text object.
+type SlackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func mrkdwn(text string) *SlackText {
+	return &SlackText{Type: "mrkdwn", Text: text}
+}
+
+func sectionBlock(text string) SlackBlock {
+	return SlackBlock{Type: "section", Text: mrkdwn(text)}
+}
+
+func fieldsBlock(fields ...string) SlackBlock {
+	block := SlackBlock{Type: "section"}
+	for _, field := range fields {
+		block.Fields = append(block.Fields, mrkdwn(field))
+	}
+	return block
+}
+
+func dividerBlock() SlackBlock {
+	return SlackBlock{Type: "divider"}
+}
+
+// bucketDelta is the change in a bucket's size between two snapshots.
+type bucketDelta struct {
+	Name        string
+	DeltaBytes  int64
+	DeltaPct    float64
+	CurrentSize int64
+}
+
+// growthBlocks builds the Block Kit body: current total, day-over-day
+// and week-over-week totals deltas, and the top N growers/shrinkers by
+// absolute byte change in each window.
+func growthBlocks(totalSizeGB float64, current map[string]*BucketInfo, dayAgo, weekAgo *Snapshot, topN int) []SlackBlock {
+	blocks := []SlackBlock{
+		sectionBlock(fmt.Sprintf("*Daily S3 size report*\n%.2f GiB total", totalSizeGB)),
+	}
+
+	if dayAgo != nil {
+		blocks = append(blocks, dividerBlock())
+		blocks = append(blocks, totalDeltaBlock("Day over day", current, dayAgo))
+		blocks = append(blocks, topMoversBlocks("Day over day", current, dayAgo, topN)...)
+	}
+	if weekAgo != nil {
+		blocks = append(blocks, dividerBlock())
+		blocks = append(blocks, totalDeltaBlock("Week over week", current, weekAgo))
+		blocks = append(blocks, topMoversBlocks("Week over week", current, weekAgo, topN)...)
+	}
+
+	return blocks
+}
+
+func totalSize(buckets map[string]*BucketInfo) int64 {
+	var total int64
+	for _, bucketInfo := range buckets {
+		total += bucketInfo.Size
+	}
+	return total
+}
+
+func totalDeltaBlock(label string, current map[string]*BucketInfo, previous *Snapshot) SlackBlock {
+	currentTotal := totalSize(current)
+	previousTotal := totalSize(previous.Buckets)
+	deltaBytes := currentTotal - previousTotal
+	deltaPct := percentChange(previousTotal, currentTotal)
+
+	return fieldsBlock(
+		fmt.Sprintf("*%s*", label),
+		fmt.Sprintf("%s%s (%s)", sign(deltaBytes), formatBytes(absInt64(deltaBytes)), formatPercent(deltaPct)),
+	)
+}
+
+// topMoversBlocks lists the topN buckets with the largest byte increase
+// ("growers") and the topN with the largest decrease ("shrinkers")
+// between previous and current.
+func topMoversBlocks(label string, current map[string]*BucketInfo, previous *Snapshot, topN int) []SlackBlock {
+	deltas := bucketDeltas(current, previous.Buckets)
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	sort.Slice(deltas, func(i, j int) bool { return deltas[i].DeltaBytes > deltas[j].DeltaBytes })
+
+	var growers, shrinkers []bucketDelta
+	for _, delta := range deltas {
+		if delta.DeltaBytes > 0 {
+			growers = append(growers, delta)
+		} else if delta.DeltaBytes < 0 {
+			shrinkers = append(shrinkers, delta)
+		}
+	}
+	if len(growers) > topN {
+		growers = growers[:topN]
+	}
+
+	sort.Slice(shrinkers, func(i, j int) bool { return shrinkers[i].DeltaBytes < shrinkers[j].DeltaBytes })
+	if len(shrinkers) > topN {
+		shrinkers = shrinkers[:topN]
+	}
+
+	return []SlackBlock{
+		sectionBlock(fmt.Sprintf("*%s top growers*\n%s", label, formatMovers(growers))),
+		sectionBlock(fmt.Sprintf("*%s top shrinkers*\n%s", label, formatMovers(shrinkers))),
+	}
+}
+
+func bucketDeltas(current map[string]*BucketInfo, previous map[string]*BucketInfo) []bucketDelta {
+	var deltas []bucketDelta
+	for name, bucketInfo := range current {
+		prevSize := int64(0)
+		if prevInfo, ok := previous[name]; ok {
+			prevSize = prevInfo.Size
+		}
+		deltas = append(deltas, bucketDelta{
+			Name:        name,
+			DeltaBytes:  bucketInfo.Size - prevSize,
+			DeltaPct:    percentChange(prevSize, bucketInfo.Size),
+			CurrentSize: bucketInfo.Size,
+		})
+	}
+
+	// A bucket that vanished since the previous snapshot (deleted, renamed,
+	// or no longer scanned) is its own top shrinker and must show up too.
+	for name, prevInfo := range previous {
+		if _, ok := current[name]; ok {
+			continue
+		}
+		deltas = append(deltas, bucketDelta{
+			Name:        name,
+			DeltaBytes:  -prevInfo.Size,
+			DeltaPct:    percentChange(prevInfo.Size, 0),
+			CurrentSize: 0,
+		})
+	}
+
+	return deltas
+}
+
+func formatMovers(deltas []bucketDelta) string {
+	if len(deltas) == 0 {
+		return "_none_"
+	}
+	out := ""
+	for _, delta := range deltas {
+		out += fmt.Sprintf("%s %s%s (%s)\n", delta.Name, sign(delta.DeltaBytes), formatBytes(absInt64(delta.DeltaBytes)), formatPercent(delta.DeltaPct))
+	}
+	return out
+}
+
+func percentChange(previous, current int64) float64 {
+	if previous == 0 {
+		if current == 0 {
+			return 0
+		}
+		return 100
+	}
+	return (float64(current) - float64(previous)) / float64(previous) * 100
+}
+
+func sign(n int64) string {
+	if n < 0 {
+		return "-"
+	}
+	return "+"
+}
+
+func absInt64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func formatBytes(n int64) string {
+	return fmt.Sprintf("%.2f GiB", float64(n)/math.Pow(1024, 3))
+}
+
+func formatPercent(pct float64) string {
+	return fmt.Sprintf("%.1f%%", pct)
+}
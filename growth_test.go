@@ -0,0 +1,66 @@
+package main
+
+import "testing"
+
+func TestPercentChange(t *testing.T) {
+	cases := []struct {
+		name     string
+		previous int64
+		current  int64
+		want     float64
+	}{
+		{"no change", 100, 100, 0},
+		{"doubled", 100, 200, 100},
+		{"halved", 100, 50, -50},
+		{"from zero to zero", 0, 0, 0},
+		{"from zero to nonzero", 0, 50, 100},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := percentChange(tc.previous, tc.current); got != tc.want {
+				t.Errorf("percentChange(%d, %d) = %v, want %v", tc.previous, tc.current, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBucketDeltas(t *testing.T) {
+	current := map[string]*BucketInfo{
+		"grew":   {Name: "grew", Size: 200},
+		"steady": {Name: "steady", Size: 50},
+		"new":    {Name: "new", Size: 10},
+	}
+	previous := map[string]*BucketInfo{
+		"grew":    {Name: "grew", Size: 100},
+		"steady":  {Name: "steady", Size: 50},
+		"deleted": {Name: "deleted", Size: 75},
+	}
+
+	deltas := bucketDeltas(current, previous)
+	byName := make(map[string]bucketDelta, len(deltas))
+	for _, delta := range deltas {
+		byName[delta.Name] = delta
+	}
+
+	if len(deltas) != 4 {
+		t.Fatalf("got %d deltas, want 4 (3 current + 1 removed): %+v", len(deltas), deltas)
+	}
+
+	if got := byName["grew"].DeltaBytes; got != 100 {
+		t.Errorf("grew DeltaBytes = %d, want 100", got)
+	}
+	if got := byName["new"].DeltaBytes; got != 10 {
+		t.Errorf("new DeltaBytes = %d, want 10", got)
+	}
+
+	deleted, ok := byName["deleted"]
+	if !ok {
+		t.Fatalf("bucket removed since the previous snapshot is missing from deltas: %+v", deltas)
+	}
+	if deleted.DeltaBytes != -75 {
+		t.Errorf("deleted DeltaBytes = %d, want -75", deleted.DeltaBytes)
+	}
+	if deleted.CurrentSize != 0 {
+		t.Errorf("deleted CurrentSize = %d, want 0", deleted.CurrentSize)
+	}
+}
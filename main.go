@@ -1,130 +1,127 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
-	"math"
-	"net/http"
 	"os"
-	"sync"
-
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"time"
 )
 
 type BucketInfo struct {
 	Name    string
+	Profile string
 	Size    int64
 	Objects int
 }
 
-type SlackPayload struct {
-	Channel   string `json:"channel"`
-	Text      string `json:"text"`
-	IconEmoji string `json:"icon_emoji"`
-}
-
-func reportStorageSize(results map[string]*BucketInfo) {
-	var totalSizeB int64
-
-	for _, bucketInfo := range results {
-		totalSizeB += bucketInfo.Size
-	}
-	totalSizeGB := float64(totalSizeB) / math.Pow(1024, 3)
-
-	log.Printf("Total S3 size: %.2f GiB", totalSizeGB)
+func main() {
+	log.Print("Starting S3 size report")
 
-	slackPayload := SlackPayload{
-		Channel:   os.Getenv("SLACK_CHANNEL"),
-		Text:      fmt.Sprintf("Daily S3 size report: %.2f GiB", totalSizeGB),
-		IconEmoji: ":bucket:",
+	ctx := context.Background()
+	s3Config := LoadS3Config()
+	sourceConfig := LoadSourceConfig()
+	scannerConfig := LoadScannerConfig()
+
+	// A plain default-profile client is enough for the reporter's own
+	// needs (e.g. an S3-backed snapshot store); per-profile scanning uses
+	// its own clients built in scanAllProfiles.
+	defaultS3Client, err := NewS3Client(ctx, s3Config, "")
+	if err != nil {
+		log.Fatalf("Failed building S3 client: %v", err)
 	}
 
-	payload, err := json.Marshal(slackPayload)
+	reporter, err := NewReporter(defaultS3Client)
 	if err != nil {
-		log.Fatalf("Failed encoding payload: %v", err)
+		log.Fatalf("Failed building reporter: %v", err)
 	}
 
-	req, err := http.NewRequest("POST", os.Getenv("SLACK_WEBHOOK_URL"), bytes.NewBuffer(payload))
-	if err != nil {
-		log.Fatalf("Failed building POST request: %v", err)
+	// The Prometheus reporter serves a long-lived scrape endpoint instead
+	// of producing a single report, so it runs the scan on a timer.
+	if _, ok := reporter.(*PrometheusReporter); ok {
+		runServer(ctx, s3Config, sourceConfig, scannerConfig, reporter)
+		return
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	results, err := scanAllProfiles(ctx, s3Config, sourceConfig, scannerConfig)
 	if err != nil {
-		log.Fatalf("Failed POSTing request: %v", err)
+		log.Fatalf("Failed scanning buckets: %v", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		log.Fatalf("Server returned status code %d: %v", resp.StatusCode, err)
+	if err := reporter.Report(ctx, results); err != nil {
+		log.Fatalf("Failed reporting results: %v", err)
 	}
 }
 
-func main() {
-	results := make(map[string]*BucketInfo)
-
-	log.Print("Starting daily S3 size report")
-
-	ctx := context.Background()
-	sdkConfig, err := config.LoadDefaultConfig(ctx)
-	if err != nil {
-		panic(err)
+// scanAllProfiles scans every configured profile (or just the default
+// credentials chain, if none are configured) and merges the results into
+// a single map keyed by "profile/bucket".
+func scanAllProfiles(ctx context.Context, s3Config S3Config, sourceConfig SourceConfig, scannerConfig ScannerConfig) (map[string]*BucketInfo, error) {
+	profiles := s3Config.Profiles
+	if len(profiles) == 0 {
+		profiles = []string{""}
 	}
 
-	s3Client := s3.NewFromConfig(sdkConfig)
-	output, err := s3Client.ListBuckets(ctx, &s3.ListBucketsInput{})
-	if err != nil {
-		panic(err)
+	results := make(map[string]*BucketInfo)
+	for _, profile := range profiles {
+		s3Client, err := NewS3Client(ctx, s3Config, profile)
+		if err != nil {
+			return nil, fmt.Errorf("profile %q: %w", profile, err)
+		}
+
+		scanner := NewScanner(s3Client, sourceConfig, scannerConfig)
+		bucketInfoCh, err := scanner.Scan(ctx, profile)
+		if err != nil {
+			return nil, fmt.Errorf("profile %q: %w", profile, err)
+		}
+		for bucketInfo := range bucketInfoCh {
+			bucketInfo := bucketInfo
+			results[profile+"/"+bucketInfo.Name] = &bucketInfo
+		}
 	}
 
-	var wg sync.WaitGroup
-	var mu sync.RWMutex
-
-	for _, bucket := range output.Buckets {
-		wg.Add(1)
+	return results, nil
+}
 
-		go func(bucketName string, s3Client *s3.Client, ctx context.Context, results map[string]*BucketInfo) {
-			defer wg.Done()
+// runServer repeatedly scans buckets on an interval, feeding the
+// Prometheus gauges, while serving /metrics until the process is killed.
+func runServer(ctx context.Context, s3Config S3Config, sourceConfig SourceConfig, scannerConfig ScannerConfig, reporter Reporter) {
+	addr := os.Getenv("METRICS_ADDR")
+	if addr == "" {
+		addr = ":9090"
+	}
 
-			var bucketSize int64
-			var objectsCount int
+	interval := 5 * time.Minute
+	if raw := os.Getenv("SCAN_INTERVAL"); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil {
+			interval = parsed
+		}
+	}
 
-			input := &s3.ListObjectsV2Input{
-				Bucket: aws.String(bucketName),
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			start := time.Now()
+			results, err := scanAllProfiles(ctx, s3Config, sourceConfig, scannerConfig)
+			if err != nil {
+				scanErrorsTotal.Inc()
+				log.Printf("Scan failed: %v", err)
+			} else if err := reporter.Report(ctx, results); err != nil {
+				log.Printf("Failed updating metrics: %v", err)
 			}
+			scanDurationSeconds.Set(time.Since(start).Seconds())
 
-			objectPaginator := s3.NewListObjectsV2Paginator(s3Client, input)
-			for objectPaginator.HasMorePages() {
-				page, err := objectPaginator.NextPage(ctx)
-				if err != nil {
-					log.Printf("Bucket %s skipped", *page.Name)
-					continue
-				}
-
-				for _, object := range page.Contents {
-					bucketSize += *object.Size
-					objectsCount++
-				}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
 			}
+		}
+	}()
 
-			mu.Lock()
-			results[bucketName] = &BucketInfo{
-				Name:    bucketName,
-				Size:    bucketSize,
-				Objects: objectsCount,
-			}
-			mu.Unlock()
-		}(*bucket.Name, s3Client, ctx, results)
+	log.Printf("Serving Prometheus metrics on %s/metrics", addr)
+	if err := runMetricsServer(ctx, addr); err != nil {
+		log.Fatalf("Metrics server failed: %v", err)
 	}
-
-	wg.Wait()
-
-	reportStorageSize(results)
 }
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	bucketSizeBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "s3_bucket_size_bytes",
+		Help: "Total size in bytes of all objects in the bucket.",
+	}, []string{"bucket", "profile"})
+
+	bucketObjectsTotal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "s3_bucket_objects_total",
+		Help: "Total number of objects in the bucket.",
+	}, []string{"bucket", "profile"})
+
+	scanDurationSeconds = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "s3_scan_duration_seconds",
+		Help: "Duration of the most recent full bucket scan, in seconds.",
+	})
+
+	scanErrorsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "s3_scan_errors_total",
+		Help: "Number of scan failures, cumulative across scans: one per bucket that failed to scan, plus one per scan run that failed as a whole.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(bucketSizeBytes, bucketObjectsTotal, scanDurationSeconds, scanErrorsTotal)
+}
+
+// PrometheusReporter updates the package-level gauges/counters from scan
+// results; the values are served to scrapers by runMetricsServer.
+type PrometheusReporter struct{}
+
+func NewPrometheusReporter() *PrometheusReporter {
+	return &PrometheusReporter{}
+}
+
+func (r *PrometheusReporter) Report(ctx context.Context, results map[string]*BucketInfo) error {
+	// Reset before repopulating so buckets that dropped out of this scan
+	// (deleted, renamed, or filtered out) don't linger in /metrics forever.
+	bucketSizeBytes.Reset()
+	bucketObjectsTotal.Reset()
+
+	for _, bucketInfo := range results {
+		bucketSizeBytes.WithLabelValues(bucketInfo.Name, bucketInfo.Profile).Set(float64(bucketInfo.Size))
+		bucketObjectsTotal.WithLabelValues(bucketInfo.Name, bucketInfo.Profile).Set(float64(bucketInfo.Objects))
+	}
+	return nil
+}
+
+// runMetricsServer serves the Prometheus scrape endpoint until ctx is
+// canceled. It's used when REPORTER=prometheus runs the tool as a
+// long-lived server instead of a one-shot report.
+func runMetricsServer(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	server := &http.Server{Addr: addr, Handler: mux}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case <-ctx.Done():
+		return server.Shutdown(context.Background())
+	case err := <-errCh:
+		if err != nil && err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Reporter publishes a completed scan's results to some destination
+// (Slack, stdout, a Prometheus scrape endpoint, CloudWatch, ...).
+type Reporter interface {
+	Report(ctx context.Context, results map[string]*BucketInfo) error
+}
+
+// NewReporter builds the Reporter selected by the REPORTER env var.
+// It defaults to "slack" to preserve the tool's original behavior.
+// s3Client is only used when REPORTER=slack and SNAPSHOT_STORE=s3.
+func NewReporter(s3Client *s3.Client) (Reporter, error) {
+	switch kind := os.Getenv("REPORTER"); kind {
+	case "", "slack":
+		snapshotStore, err := NewSnapshotStore(LoadSnapshotConfig(), s3Client)
+		if err != nil {
+			return nil, fmt.Errorf("failed building snapshot store: %w", err)
+		}
+		topN := 5
+		if raw := os.Getenv("SLACK_TOP_N"); raw != "" {
+			if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 {
+				topN = parsed
+			}
+		}
+		return &SlackReporter{
+			WebhookURL:    os.Getenv("SLACK_WEBHOOK_URL"),
+			Channel:       os.Getenv("SLACK_CHANNEL"),
+			SnapshotStore: snapshotStore,
+			TopN:          topN,
+		}, nil
+	case "stdout":
+		return &StdoutReporter{}, nil
+	case "prometheus":
+		return NewPrometheusReporter(), nil
+	case "cloudwatch":
+		return NewCloudWatchReporter(), nil
+	default:
+		return nil, fmt.Errorf("unknown REPORTER %q", kind)
+	}
+}
+
+// SlackReporter posts a size report to a Slack incoming webhook. When
+// SnapshotStore is set, it also persists the run and includes
+// day-over-day/week-over-week growth in the message.
+type SlackReporter struct {
+	WebhookURL    string
+	Channel       string
+	SnapshotStore SnapshotStore
+	TopN          int
+}
+
+// SlackPayload is a Slack incoming-webhook request body using Block Kit
+// blocks instead of a single text string, so the report can lay out
+// fields and dividers like a small table.
+type SlackPayload struct {
+	Channel   string       `json:"channel"`
+	IconEmoji string       `json:"icon_emoji"`
+	Text      string       `json:"text"`
+	Blocks    []SlackBlock `json:"blocks"`
+}
+
+func (r *SlackReporter) Report(ctx context.Context, results map[string]*BucketInfo) error {
+	var totalSizeB int64
+	for _, bucketInfo := range results {
+		totalSizeB += bucketInfo.Size
+	}
+	totalSizeGB := float64(totalSizeB) / math.Pow(1024, 3)
+
+	log.Printf("Total S3 size: %.2f GiB", totalSizeGB)
+
+	blocks, err := r.buildBlocks(ctx, results, totalSizeGB)
+	if err != nil {
+		log.Printf("Failed computing growth deltas, reporting totals only: %v", err)
+		blocks = totalsOnlyBlocks(totalSizeGB)
+	}
+
+	slackPayload := SlackPayload{
+		Channel:   r.Channel,
+		IconEmoji: ":bucket:",
+		Text:      fmt.Sprintf("Daily S3 size report: %.2f GiB", totalSizeGB),
+		Blocks:    blocks,
+	}
+
+	payload, err := json.Marshal(slackPayload)
+	if err != nil {
+		return fmt.Errorf("failed encoding payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", r.WebhookURL, bytes.NewBuffer(payload))
+	if err != nil {
+		return fmt.Errorf("failed building POST request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed POSTing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("slack webhook returned status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// buildBlocks persists today's snapshot (if a SnapshotStore is
+// configured) and builds the Block Kit message body, including
+// day-over-day/week-over-week deltas and the top growers/shrinkers.
+func (r *SlackReporter) buildBlocks(ctx context.Context, results map[string]*BucketInfo, totalSizeGB float64) ([]SlackBlock, error) {
+	if r.SnapshotStore == nil {
+		return totalsOnlyBlocks(totalSizeGB), nil
+	}
+
+	now := time.Now()
+	if err := r.SnapshotStore.Save(ctx, Snapshot{Timestamp: now, Buckets: results}); err != nil {
+		return nil, fmt.Errorf("failed saving snapshot: %w", err)
+	}
+
+	dayAgo, err := r.SnapshotStore.Load(ctx, now.AddDate(0, 0, -1))
+	if err != nil {
+		return nil, fmt.Errorf("failed loading previous day's snapshot: %w", err)
+	}
+	weekAgo, err := r.SnapshotStore.Load(ctx, now.AddDate(0, 0, -7))
+	if err != nil {
+		return nil, fmt.Errorf("failed loading previous week's snapshot: %w", err)
+	}
+
+	return growthBlocks(totalSizeGB, results, dayAgo, weekAgo, r.TopN), nil
+}
+
+func totalsOnlyBlocks(totalSizeGB float64) []SlackBlock {
+	return []SlackBlock{
+		sectionBlock(fmt.Sprintf("*Daily S3 size report*\n%.2f GiB total", totalSizeGB)),
+	}
+}
+
+// StdoutReporter prints per-bucket and total sizes to stdout. Handy for
+// local runs and for piping into other tooling.
+type StdoutReporter struct{}
+
+func (r *StdoutReporter) Report(ctx context.Context, results map[string]*BucketInfo) error {
+	var totalSizeB int64
+	for _, bucketInfo := range results {
+		totalSizeB += bucketInfo.Size
+		fmt.Printf("%s\t%s\t%d bytes\t%d objects\n", bucketInfo.Profile, bucketInfo.Name, bucketInfo.Size, bucketInfo.Objects)
+	}
+	totalSizeGB := float64(totalSizeB) / math.Pow(1024, 3)
+	fmt.Printf("TOTAL\t%.2f GiB\n", totalSizeGB)
+	return nil
+}
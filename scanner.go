@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"golang.org/x/sync/errgroup"
+)
+
+// ScannerConfig bounds and filters a Scanner's work.
+type ScannerConfig struct {
+	Concurrency   int
+	BucketTimeout time.Duration
+	IncludeGlobs  []string
+	ExcludeGlobs  []string
+	Regions       []string
+}
+
+// LoadScannerConfig reads scanner tuning settings from the environment.
+func LoadScannerConfig() ScannerConfig {
+	cfg := ScannerConfig{
+		Concurrency:   10,
+		BucketTimeout: time.Minute,
+	}
+
+	if raw := os.Getenv("SCANNER_CONCURRENCY"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 {
+			cfg.Concurrency = parsed
+		}
+	}
+	if raw := os.Getenv("BUCKET_SCAN_TIMEOUT"); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil {
+			cfg.BucketTimeout = parsed
+		}
+	}
+	cfg.IncludeGlobs = splitAndTrim(os.Getenv("BUCKET_INCLUDE"))
+	cfg.ExcludeGlobs = splitAndTrim(os.Getenv("BUCKET_EXCLUDE"))
+	cfg.Regions = splitAndTrim(os.Getenv("SCAN_REGIONS"))
+
+	return cfg
+}
+
+func splitAndTrim(raw string) []string {
+	if raw == "" {
+		return nil
+	}
+	var out []string
+	for _, part := range strings.Split(raw, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// Scanner scans every bucket visible to an S3 client with a bounded
+// worker pool, retrying transient failures and streaming results back
+// over a channel so one bucket's failure can't silently zero out the
+// whole run.
+type Scanner struct {
+	S3Client     *s3.Client
+	SourceConfig SourceConfig
+	Config       ScannerConfig
+}
+
+func NewScanner(s3Client *s3.Client, sourceConfig SourceConfig, scannerConfig ScannerConfig) *Scanner {
+	return &Scanner{S3Client: s3Client, SourceConfig: sourceConfig, Config: scannerConfig}
+}
+
+// Scan lists every bucket, applies the include/exclude/region filters,
+// then scans the survivors concurrently (bounded by Config.Concurrency).
+// Results are tagged with profile and streamed on the returned channel,
+// which is closed once every bucket has been attempted.
+func (s *Scanner) Scan(ctx context.Context, profile string) (<-chan BucketInfo, error) {
+	output, err := s.S3Client.ListBuckets(ctx, &s3.ListBucketsInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	// Buffered for every bucket so workers never block on send while the
+	// loop below is still queuing work before the channel is returned.
+	results := make(chan BucketInfo, len(output.Buckets))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(s.Config.Concurrency)
+
+	for _, bucket := range output.Buckets {
+		bucketName := *bucket.Name
+		if !s.matchesFilters(bucketName) {
+			continue
+		}
+
+		g.Go(func() error {
+			if len(s.Config.Regions) > 0 {
+				inRegion, err := s.bucketInRegion(gctx, bucketName)
+				if err != nil {
+					log.Printf("Bucket %s: failed checking region, skipping: %v", bucketName, err)
+					scanErrorsTotal.Inc()
+					return nil
+				}
+				if !inRegion {
+					return nil
+				}
+			}
+
+			bucketInfo, err := s.scanBucketWithTimeout(gctx, bucketName)
+			if err != nil {
+				log.Printf("Bucket %s: skipped after retries: %v", bucketName, err)
+				scanErrorsTotal.Inc()
+				return nil
+			}
+			bucketInfo.Profile = profile
+
+			select {
+			case results <- *bucketInfo:
+				return nil
+			case <-gctx.Done():
+				return gctx.Err()
+			}
+		})
+	}
+
+	go func() {
+		if err := g.Wait(); err != nil {
+			log.Printf("Scan stopped early: %v", err)
+		}
+		close(results)
+	}()
+
+	return results, nil
+}
+
+// scanBucketWithTimeout bounds a single bucket's scan with its own
+// context deadline, so one stuck bucket can't stall the whole run. Retry
+// of transient S3 errors (SlowDown, RequestLimitExceeded, 5xx) is handled
+// below this by the SDK's standard retryer configured in NewS3Client.
+func (s *Scanner) scanBucketWithTimeout(ctx context.Context, bucketName string) (*BucketInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.Config.BucketTimeout)
+	defer cancel()
+
+	source := SelectSource(ctx, s.SourceConfig, s.S3Client, bucketName)
+	return source.Scan(ctx, s.S3Client, bucketName)
+}
+
+func (s *Scanner) matchesFilters(bucketName string) bool {
+	if len(s.Config.IncludeGlobs) > 0 && !matchesAny(s.Config.IncludeGlobs, bucketName) {
+		return false
+	}
+	return !matchesAny(s.Config.ExcludeGlobs, bucketName)
+}
+
+func matchesAny(globs []string, name string) bool {
+	for _, glob := range globs {
+		if ok, err := filepath.Match(glob, name); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scanner) bucketInRegion(ctx context.Context, bucketName string) (bool, error) {
+	output, err := s.S3Client.GetBucketLocation(ctx, &s3.GetBucketLocationInput{Bucket: aws.String(bucketName)})
+	if err != nil {
+		return false, fmt.Errorf("failed getting bucket location: %w", err)
+	}
+
+	region := string(output.LocationConstraint)
+	if region == "" {
+		region = "us-east-1" // empty constraint means the bucket is in us-east-1
+	}
+
+	for _, want := range s.Config.Regions {
+		if want == region {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestMatchesAny(t *testing.T) {
+	cases := []struct {
+		name  string
+		globs []string
+		input string
+		want  bool
+	}{
+		{"no globs", nil, "my-bucket", false},
+		{"exact match", []string{"my-bucket"}, "my-bucket", true},
+		{"wildcard match", []string{"logs-*"}, "logs-prod", true},
+		{"no match among several", []string{"foo-*", "bar-*"}, "my-bucket", false},
+		{"match among several", []string{"foo-*", "logs-*"}, "logs-prod", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := matchesAny(tc.globs, tc.input); got != tc.want {
+				t.Errorf("matchesAny(%v, %q) = %v, want %v", tc.globs, tc.input, got, tc.want)
+			}
+		})
+	}
+}
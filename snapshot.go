@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Snapshot is the per-run data persisted so later runs can compute
+// growth deltas.
+type Snapshot struct {
+	Timestamp time.Time              `json:"timestamp"`
+	Buckets   map[string]*BucketInfo `json:"buckets"`
+}
+
+// SnapshotStore persists and retrieves daily snapshots of scan results.
+type SnapshotStore interface {
+	Save(ctx context.Context, snapshot Snapshot) error
+	// Load returns the snapshot for the given day (truncated to a date),
+	// or nil if none was recorded.
+	Load(ctx context.Context, day time.Time) (*Snapshot, error)
+}
+
+// SnapshotConfig selects and configures the SnapshotStore.
+type SnapshotConfig struct {
+	// Backend is one of "local", "s3", or "" (disabled).
+	Backend string
+
+	LocalDir string
+
+	S3Bucket string
+	S3Prefix string
+}
+
+// LoadSnapshotConfig reads snapshot-store settings from the environment.
+func LoadSnapshotConfig() SnapshotConfig {
+	return SnapshotConfig{
+		Backend:  os.Getenv("SNAPSHOT_STORE"),
+		LocalDir: os.Getenv("SNAPSHOT_DIR"),
+		S3Bucket: os.Getenv("SNAPSHOT_S3_BUCKET"),
+		S3Prefix: os.Getenv("SNAPSHOT_S3_PREFIX"),
+	}
+}
+
+// NewSnapshotStore builds the SnapshotStore selected by cfg.Backend, or
+// nil if snapshotting is disabled.
+func NewSnapshotStore(cfg SnapshotConfig, s3Client *s3.Client) (SnapshotStore, error) {
+	switch cfg.Backend {
+	case "":
+		return nil, nil
+	case "local":
+		dir := cfg.LocalDir
+		if dir == "" {
+			dir = "snapshots"
+		}
+		return &LocalSnapshotStore{Dir: dir}, nil
+	case "s3":
+		if cfg.S3Bucket == "" {
+			return nil, fmt.Errorf("SNAPSHOT_S3_BUCKET is required when SNAPSHOT_STORE=s3")
+		}
+		return &S3SnapshotStore{S3Client: s3Client, Bucket: cfg.S3Bucket, Prefix: cfg.S3Prefix}, nil
+	default:
+		return nil, fmt.Errorf("unknown SNAPSHOT_STORE %q", cfg.Backend)
+	}
+}
+
+func snapshotFileName(day time.Time) string {
+	return day.UTC().Format("2006-01-02") + ".json"
+}
+
+// LocalSnapshotStore writes one JSON file per day to a local directory.
+type LocalSnapshotStore struct {
+	Dir string
+}
+
+func (s *LocalSnapshotStore) Save(ctx context.Context, snapshot Snapshot) error {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return fmt.Errorf("failed creating snapshot dir: %w", err)
+	}
+
+	payload, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed encoding snapshot: %w", err)
+	}
+
+	path := filepath.Join(s.Dir, snapshotFileName(snapshot.Timestamp))
+	if err := os.WriteFile(path, payload, 0o644); err != nil {
+		return fmt.Errorf("failed writing snapshot: %w", err)
+	}
+	return nil
+}
+
+func (s *LocalSnapshotStore) Load(ctx context.Context, day time.Time) (*Snapshot, error) {
+	path := filepath.Join(s.Dir, snapshotFileName(day))
+	payload, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed reading snapshot: %w", err)
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(payload, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed decoding snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// S3SnapshotStore writes one JSON object per day to
+// s3://Bucket/Prefix/YYYY-MM-DD.json, so reports persist across
+// ephemeral runs without needing a local disk.
+type S3SnapshotStore struct {
+	S3Client *s3.Client
+	Bucket   string
+	Prefix   string
+}
+
+func (s *S3SnapshotStore) key(day time.Time) string {
+	prefix := strings.TrimSuffix(s.Prefix, "/")
+	if prefix == "" {
+		return snapshotFileName(day)
+	}
+	return prefix + "/" + snapshotFileName(day)
+}
+
+func (s *S3SnapshotStore) Save(ctx context.Context, snapshot Snapshot) error {
+	payload, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed encoding snapshot: %w", err)
+	}
+
+	_, err = s.S3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.key(snapshot.Timestamp)),
+		Body:   bytes.NewReader(payload),
+	})
+	if err != nil {
+		return fmt.Errorf("failed uploading snapshot: %w", err)
+	}
+	return nil
+}
+
+func (s *S3SnapshotStore) Load(ctx context.Context, day time.Time) (*Snapshot, error) {
+	obj, err := s.S3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.key(day)),
+	})
+	if isNoSuchKey(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed downloading snapshot: %w", err)
+	}
+	defer obj.Body.Close()
+
+	payload, err := io.ReadAll(obj.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading snapshot: %w", err)
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(payload, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed decoding snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
+
+func isNoSuchKey(err error) bool {
+	var nsk *types.NoSuchKey
+	return errors.As(err, &nsk)
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSnapshotFileName(t *testing.T) {
+	// 23:30 in UTC-5 is already the next day in UTC; snapshotFileName
+	// must key off the UTC date, not the local one.
+	day := time.Date(2026, 3, 5, 23, 30, 0, 0, time.FixedZone("UTC-5", -5*60*60))
+	want := "2026-03-06.json"
+	if got := snapshotFileName(day); got != want {
+		t.Errorf("snapshotFileName(%v) = %q, want %q", day, got, want)
+	}
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// BucketSource knows how to produce size/object totals for a single
+// bucket. Different implementations trade cost and latency against
+// freshness and precision.
+type BucketSource interface {
+	Scan(ctx context.Context, s3Client *s3.Client, bucketName string) (*BucketInfo, error)
+}
+
+// ListSource walks every object in the bucket with ListObjectsV2. It is
+// exact and fresh but, for buckets with hundreds of millions of objects,
+// slow and expensive in API calls.
+type ListSource struct{}
+
+func (s *ListSource) Scan(ctx context.Context, s3Client *s3.Client, bucketName string) (*BucketInfo, error) {
+	var bucketSize int64
+	var objectsCount int
+
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(bucketName)}
+	paginator := s3.NewListObjectsV2Paginator(s3Client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, object := range page.Contents {
+			bucketSize += *object.Size
+			objectsCount++
+		}
+	}
+
+	return &BucketInfo{Name: bucketName, Size: bucketSize, Objects: objectsCount}, nil
+}
+
+// SourceConfig selects and configures the BucketSource used per bucket.
+type SourceConfig struct {
+	// Mode is one of "list", "inventory", "cloudwatch", or "auto".
+	// "auto" uses an S3 Inventory manifest when one exists and is fresh
+	// enough, and falls back to ListSource otherwise.
+	Mode string
+
+	InventoryBucket string
+	InventoryPrefix string
+	MaxManifestAge  time.Duration
+}
+
+// LoadSourceConfig reads bucket-scanning source settings from the
+// environment.
+func LoadSourceConfig() SourceConfig {
+	cfg := SourceConfig{
+		Mode:            os.Getenv("BUCKET_SOURCE"),
+		InventoryBucket: os.Getenv("INVENTORY_BUCKET"),
+		InventoryPrefix: os.Getenv("INVENTORY_PREFIX"),
+		MaxManifestAge:  24 * time.Hour,
+	}
+	if cfg.Mode == "" {
+		cfg.Mode = "list"
+	}
+	if raw := os.Getenv("INVENTORY_MAX_AGE"); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil {
+			cfg.MaxManifestAge = parsed
+		}
+	}
+	return cfg
+}
+
+// SelectSource picks the BucketSource to use for bucketName, honoring
+// cfg.Mode and, in "auto" mode, whether a fresh-enough inventory manifest
+// exists for the bucket.
+func SelectSource(ctx context.Context, cfg SourceConfig, inventoryClient *s3.Client, bucketName string) BucketSource {
+	switch cfg.Mode {
+	case "inventory":
+		return &InventorySource{S3Client: inventoryClient, InventoryBucket: cfg.InventoryBucket, InventoryPrefix: cfg.InventoryPrefix}
+	case "cloudwatch":
+		return &CloudWatchMetricsSource{}
+	case "auto":
+		inv := &InventorySource{S3Client: inventoryClient, InventoryBucket: cfg.InventoryBucket, InventoryPrefix: cfg.InventoryPrefix}
+		if manifest, err := inv.latestManifest(ctx, bucketName); err == nil && time.Since(manifest.lastModified) < cfg.MaxManifestAge {
+			return inv
+		}
+		return &ListSource{}
+	default:
+		return &ListSource{}
+	}
+}
+
+// InventorySource sums object sizes from the newest S3 Inventory report
+// for a bucket instead of listing every object, trading some freshness
+// for dramatically fewer API calls on very large buckets.
+type InventorySource struct {
+	S3Client        *s3.Client
+	InventoryBucket string
+	InventoryPrefix string
+}
+
+type inventoryManifest struct {
+	key          string
+	lastModified time.Time
+}
+
+// inventoryManifestPrefix builds the prefix under which bucketName's
+// inventory manifests live, given the configured (possibly empty)
+// InventoryPrefix.
+func inventoryManifestPrefix(inventoryPrefix, bucketName string) string {
+	if inventoryPrefix == "" {
+		return bucketName + "/"
+	}
+	return strings.TrimSuffix(inventoryPrefix, "/") + "/" + bucketName + "/"
+}
+
+// latestManifest returns the most recently written manifest.json under
+// InventoryPrefix/bucketName/, relying on S3 Inventory's convention of
+// one dated subfolder per delivery.
+func (s *InventorySource) latestManifest(ctx context.Context, bucketName string) (*inventoryManifest, error) {
+	prefix := inventoryManifestPrefix(s.InventoryPrefix, bucketName)
+
+	var newest *inventoryManifest
+	var continuationToken *string
+	for {
+		page, err := s.S3Client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+			Bucket:            aws.String(s.InventoryBucket),
+			Prefix:            aws.String(prefix),
+			ContinuationToken: continuationToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed listing inventory manifests: %w", err)
+		}
+
+		for _, object := range page.Contents {
+			if !strings.HasSuffix(*object.Key, "manifest.json") {
+				continue
+			}
+			if newest == nil || object.LastModified.After(newest.lastModified) {
+				newest = &inventoryManifest{key: *object.Key, lastModified: *object.LastModified}
+			}
+		}
+
+		if !aws.ToBool(page.IsTruncated) {
+			break
+		}
+		continuationToken = page.NextContinuationToken
+	}
+
+	if newest == nil {
+		return nil, fmt.Errorf("no inventory manifest found under %s", prefix)
+	}
+	return newest, nil
+}
+
+type manifestFile struct {
+	Key  string `json:"key"`
+	Size int64  `json:"size"`
+}
+
+type manifestDocument struct {
+	SourceBucket string         `json:"sourceBucket"`
+	FileFormat   string         `json:"fileFormat"`
+	FileSchema   string         `json:"fileSchema"`
+	Files        []manifestFile `json:"files"`
+}
+
+func (s *InventorySource) Scan(ctx context.Context, s3Client *s3.Client, bucketName string) (*BucketInfo, error) {
+	manifest, err := s.latestManifest(ctx, bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := s.S3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.InventoryBucket),
+		Key:    aws.String(manifest.key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed downloading manifest: %w", err)
+	}
+	defer obj.Body.Close()
+
+	var doc manifestDocument
+	if err := json.NewDecoder(obj.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed parsing manifest: %w", err)
+	}
+
+	// TODO: only CSV manifests are supported so far; ORC and Parquet
+	// (the other two formats S3 Inventory can emit) are not yet handled.
+	if doc.FileFormat != "CSV" {
+		return nil, fmt.Errorf("inventory format %q not supported yet (only CSV)", doc.FileFormat)
+	}
+	sizeColumn, err := sizeColumnIndex(doc.FileSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	var bucketSize int64
+	var objectsCount int
+	for _, file := range doc.Files {
+		rows, size, err := s.sumCSVFile(ctx, file.Key, sizeColumn)
+		if err != nil {
+			return nil, fmt.Errorf("failed reading inventory data file %s: %w", file.Key, err)
+		}
+		bucketSize += size
+		objectsCount += rows
+	}
+
+	return &BucketInfo{Name: bucketName, Size: bucketSize, Objects: objectsCount}, nil
+}
+
+// sizeColumnIndex finds the position of the "Size" field in an S3
+// Inventory manifest's comma-separated fileSchema string, e.g.
+// "Bucket, Key, Size, LastModifiedDate".
+func sizeColumnIndex(fileSchema string) (int, error) {
+	for i, field := range strings.Split(fileSchema, ",") {
+		if strings.TrimSpace(field) == "Size" {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("fileSchema %q has no Size column", fileSchema)
+}
+
+func (s *InventorySource) sumCSVFile(ctx context.Context, key string, sizeColumn int) (rows int, totalSize int64, err error) {
+	obj, err := s.S3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.InventoryBucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	defer obj.Body.Close()
+
+	gz, err := gzip.NewReader(bufio.NewReader(obj.Body))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer gz.Close()
+
+	reader := csv.NewReader(gz)
+	reader.FieldsPerRecord = -1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return rows, totalSize, err
+		}
+		if sizeColumn >= len(record) {
+			continue
+		}
+		size, err := strconv.ParseInt(record[sizeColumn], 10, 64)
+		if err != nil {
+			continue
+		}
+		totalSize += size
+		rows++
+	}
+
+	return rows, totalSize, nil
+}
+
+// CloudWatchMetricsSource reads the BucketSizeBytes/NumberOfObjects
+// daily metrics that S3 already publishes to CloudWatch, at the cost of
+// up to a day of staleness and the loss of per-object detail.
+type CloudWatchMetricsSource struct {
+	Client *cloudwatch.Client
+}
+
+// bucketSizeStorageTypes lists the StorageType dimension values S3
+// publishes BucketSizeBytes under. A bucket's objects can be spread
+// across any of these, so they must all be summed to get the bucket's
+// true total size; reading StandardStorage alone silently under-reports
+// buckets using IA/Glacier/Intelligent-Tiering/etc.
+var bucketSizeStorageTypes = []string{
+	"StandardStorage",
+	"StandardIAStorage",
+	"OneZoneIAStorage",
+	"ReducedRedundancyStorage",
+	"GlacierInstantRetrievalStorage",
+	"GlacierStorage",
+	"DeepArchiveStorage",
+	"IntelligentTieringFAStorage",
+	"IntelligentTieringIAStorage",
+	"IntelligentTieringAAStorage",
+	"IntelligentTieringAIAStorage",
+	"IntelligentTieringDAAStorage",
+}
+
+func (s *CloudWatchMetricsSource) client(ctx context.Context) (*cloudwatch.Client, error) {
+	if s.Client != nil {
+		return s.Client, nil
+	}
+	sdkConfig, err := defaultAWSConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.Client = cloudwatch.NewFromConfig(sdkConfig)
+	return s.Client, nil
+}
+
+func (s *CloudWatchMetricsSource) Scan(ctx context.Context, s3Client *s3.Client, bucketName string) (*BucketInfo, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var size float64
+	for _, storageType := range bucketSizeStorageTypes {
+		datapoint, err := s.latestDatapoint(ctx, client, bucketName, "BucketSizeBytes", storageType)
+		if err != nil {
+			continue // most buckets don't use every storage type; absent datapoints are expected
+		}
+		size += datapoint
+	}
+	objects, err := s.latestDatapoint(ctx, client, bucketName, "NumberOfObjects", "AllStorageTypes")
+	if err != nil {
+		return nil, fmt.Errorf("failed reading NumberOfObjects: %w", err)
+	}
+
+	return &BucketInfo{Name: bucketName, Size: int64(size), Objects: int(objects)}, nil
+}
+
+func (s *CloudWatchMetricsSource) latestDatapoint(ctx context.Context, client *cloudwatch.Client, bucketName, metricName, storageType string) (float64, error) {
+	now := time.Now()
+	output, err := client.GetMetricStatistics(ctx, &cloudwatch.GetMetricStatisticsInput{
+		Namespace:  aws.String("AWS/S3"),
+		MetricName: aws.String(metricName),
+		Dimensions: []cwtypes.Dimension{
+			{Name: aws.String("BucketName"), Value: aws.String(bucketName)},
+			{Name: aws.String("StorageType"), Value: aws.String(storageType)},
+		},
+		StartTime:  aws.Time(now.Add(-2 * 24 * time.Hour)),
+		EndTime:    aws.Time(now),
+		Period:     aws.Int32(86400),
+		Statistics: []cwtypes.Statistic{cwtypes.StatisticAverage},
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(output.Datapoints) == 0 {
+		return 0, fmt.Errorf("no datapoints for %s/%s", metricName, storageType)
+	}
+
+	sort.Slice(output.Datapoints, func(i, j int) bool {
+		return output.Datapoints[i].Timestamp.After(*output.Datapoints[j].Timestamp)
+	})
+	return aws.ToFloat64(output.Datapoints[0].Average), nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import "testing"
+
+func TestSizeColumnIndex(t *testing.T) {
+	cases := []struct {
+		name       string
+		fileSchema string
+		want       int
+		wantErr    bool
+	}{
+		{"size present", "Bucket, Key, Size, LastModifiedDate", 2, false},
+		{"size first", "Size, Bucket, Key", 0, false},
+		{"no size column", "Bucket, Key, LastModifiedDate", 0, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := sizeColumnIndex(tc.fileSchema)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("sizeColumnIndex(%q) = %d, nil, want an error", tc.fileSchema, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("sizeColumnIndex(%q) returned unexpected error: %v", tc.fileSchema, err)
+			}
+			if got != tc.want {
+				t.Errorf("sizeColumnIndex(%q) = %d, want %d", tc.fileSchema, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestInventoryManifestPrefix(t *testing.T) {
+	cases := []struct {
+		name            string
+		inventoryPrefix string
+		bucketName      string
+		want            string
+	}{
+		{"no configured prefix", "", "my-bucket", "my-bucket/"},
+		{"configured prefix without trailing slash", "inventory", "my-bucket", "inventory/my-bucket/"},
+		{"configured prefix with trailing slash", "inventory/", "my-bucket", "inventory/my-bucket/"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := inventoryManifestPrefix(tc.inventoryPrefix, tc.bucketName); got != tc.want {
+				t.Errorf("inventoryManifestPrefix(%q, %q) = %q, want %q", tc.inventoryPrefix, tc.bucketName, got, tc.want)
+			}
+		})
+	}
+}